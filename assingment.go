@@ -1,147 +1,311 @@
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"sync"
-	"time"
-
-	"github.com/gorilla/mux"
-)
-
-// Cache is the main struct representing the in-memory cache
-type Cache struct {
-	mu        sync.RWMutex
-	items     map[string]*cacheItem
-	maxSize   int
-	expireTTL time.Duration
-}
-
-// cacheItem represents an item in the cache with its value and expiration time
-type cacheItem struct {
-	value      interface{}
-	expireTime time.Time
-}
-
-// cacheMap represents a map of caches, where each cache is identified by a string key
-type cacheMap struct {
-	mu     sync.RWMutex
-	caches map[string]*Cache
-}
-
-var (
-	cacheMapInstance = cacheMap{
-		caches: make(map[string]*Cache),
-	}
-)
-
-// NewCache creates a new Cache with the given maximum size and expiration TTL
-func NewCache(maxSize int, expireTTL time.Duration) *Cache {
-	return &Cache{
-		items:     make(map[string]*cacheItem),
-		maxSize:   maxSize,
-		expireTTL: expireTTL,
-	}
-}
-
-// WriteJSONResponse writes a JSON response to the HTTP response writer with the given status code and response body
-func WriteJSONResponse(w http.ResponseWriter, statusCode int, body interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(body)
-}
-
-// Set stores a value in the cache with the given key
-func (c *Cache) Set(key string, value interface{}) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Check if the cache is full and delete the oldest item if necessary
-	if len(c.items) >= c.maxSize {
-		c.deleteOldestItem()
-	}
-
-	// Set the new item in the cache with its expiration time
-	expireTime := time.Now().Add(c.expireTTL)
-	c.items[key] = &cacheItem{
-		value:      value,
-		expireTime: expireTime,
-	}
-}
-
-// Get retrieves a value from the cache given a key, returns nil if not found or expired
-func (c *Cache) Get(key string) interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	item, ok := c.items[key]
-	if ok && item.expireTime.After(time.Now()) {
-		return item.value
-	}
-
-	return nil
-}
-
-// Delete removes a value from the cache given a key
-func (c *Cache) Delete(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	delete(c.items, key)
-}
-
-// deleteOldestItem removes the oldest item from the cache based on its expiration time
-func (c *Cache) deleteOldestItem() {
-	var oldestKey string
-	var oldestTime time.Time
-
-	for key, item := range c.items {
-		if oldestTime.IsZero() || item.expireTime.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = item.expireTime
-		}
-	}
-
-	delete(c.items, oldestKey)
-}
-
-// HandleCreateCache is the handler for creating a new cache with a given maximum size and expiration TTL
-func HandleCreateCache(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	maxSize := vars["maxSize"]
-	expireTTL := vars["expireTTL"]
-
-	maxSizeInt := 0
-	expireTTLInt := 0
-
-	fmt.Sscanf(maxSize, "%d", &maxSizeInt)
-	fmt.Sscanf(expireTTL, "%d", &expireTTLInt)
-
-	if maxSizeInt <= 0 || expireTTLInt <= 0 {
-		http.Error(w, "Invalid input. Maximum size and expiration TTL must be greater than 0.", http.StatusBadRequest)
-		return
-	}
-
-	cache := NewCache(maxSizeInt, time.Duration(expireTTLInt)*time.Second)
-
-	cacheID := fmt.Sprintf("cache%d", time.Now().UnixNano())
-
-	cacheMapInstance.mu.Lock()
-	defer cacheMapInstance.mu.Unlock()
-
-	cacheMapInstance.caches[cacheID] = cache
-
-	response := map[string]string{
-		"status":  "success",
-		"message": fmt.Sprintf("Cache created with ID: %s", cacheID),
-	}
-
-	WriteJSONResponse(w, http.StatusCreated, response)
-}
-
-// In this implementation, a unique cache ID is generated based on the current UnixNano timestamp, and the created cache is stored in the cacheMapInstance
-// which is a global instance of cacheMap that holds multiple caches identified by their cache IDs. Also,
-// the WriteJSONResponse function is used to write the J6SON response to the HTTP response writer with the appropriate status code and response body.
-// Plz you have to the Gorilla Mux library installed in your Go environment. You can install it using this command:go get -u github.com/gorilla/mux
-// Thank you.
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Clock abstracts time.Now so tests can inject virtual time instead of
+// sleeping. realClock is used everywhere outside of tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock backed by the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// cacheShard is a single shard of Cache: an LRU cache with per-entry
+// expiration, modeled after k8s.io/apimachinery's LRUExpireCache. A
+// container/list.List keeps its entries ordered by recency, and a map
+// gives O(1) lookup into it. Cache fans keys out across many shards, each
+// with its own cacheShard, to avoid serializing all access behind one lock.
+type cacheShard struct {
+	mu           sync.Mutex
+	ll           *list.List
+	items        map[string]*list.Element
+	maxSize      int
+	maxBytes     int64
+	currentBytes int64
+	totalSize    *int64
+	totalBytes   *int64
+	// shards holds every shard of the same Cache, including this one, so
+	// enforceGlobalLimits can pick an eviction victim across all of them
+	// instead of just this shard's local list. It is nil for a cacheShard
+	// used standalone (e.g. in a benchmark), in which case this shard's own
+	// local LRU list is the whole cache and enforceLimits evicts from it
+	// directly.
+	shards                []*cacheShard
+	sizer                 Sizer
+	policy                EvictionPolicy
+	expireTTL             time.Duration
+	clock                 Clock
+	cleanupInterval       time.Duration
+	stopJanitor           chan struct{}
+	stopOnce              sync.Once
+	expirationCallback    func(key string, value interface{})
+	loaderFunction        func(key string) (interface{}, time.Duration, error)
+	skipTTLExtensionOnHit bool
+	loaderMu              sync.Mutex
+	loaderCalls           map[string]*loaderCall
+	hits                  uint64
+	misses                uint64
+	evictions             uint64
+}
+
+// cacheEntry is the value stored in each list.Element.
+type cacheEntry struct {
+	key        string
+	value      interface{}
+	expireTime time.Time
+	hasExpiry  bool
+	ttl        time.Duration
+	size       int64
+	hitCount   uint64
+	// lastAccess is when this entry was last Set or hit by Get. Within a
+	// shard it's redundant with list position (MoveToFront keeps the list in
+	// lastAccess order), but enforceGlobalLimits needs it to compare
+	// candidates from different shards' lists against each other.
+	lastAccess time.Time
+}
+
+// cacheMap represents a map of caches, where each cache is identified by a string key
+type cacheMap struct {
+	mu     sync.RWMutex
+	caches map[string]*Cache
+}
+
+var (
+	cacheMapInstance = cacheMap{
+		caches: make(map[string]*Cache),
+	}
+)
+
+// NoExpiration is a sentinel TTL meaning "this entry never expires".
+const NoExpiration time.Duration = -1
+
+// WriteJSONResponse writes a JSON response to the HTTP response writer with the given status code and response body
+func WriteJSONResponse(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Set stores a value in the cache with the given key, using the cache-wide
+// expireTTL. It moves the entry to the front of the LRU list.
+func (c *cacheShard) Set(key string, value interface{}) {
+	c.SetWithTTL(key, value, c.expireTTL)
+}
+
+// SetWithTTL stores a value in the cache with the given key, overriding the
+// cache-wide expireTTL for this entry. Pass NoExpiration for an entry that
+// should never expire. If the key already exists its value and position are
+// refreshed rather than creating a duplicate entry.
+//
+// If this shard is part of a sharded Cache (c.shards is set), limit
+// enforcement happens across every shard once this shard's own lock is
+// released, since the cache-wide budget can only be satisfied by evicting
+// whichever entry is globally worst, not necessarily one in this shard.
+func (c *cacheShard) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+
+	var expireTime time.Time
+	hasExpiry := ttl != NoExpiration
+	if hasExpiry {
+		expireTime = c.clock.Now().Add(ttl)
+	}
+
+	size := c.sizer(value)
+	now := c.clock.Now()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*cacheEntry)
+		delta := size - entry.size
+		c.currentBytes += delta
+		atomic.AddInt64(c.totalBytes, delta)
+		entry.value = value
+		entry.expireTime = expireTime
+		entry.hasExpiry = hasExpiry
+		entry.ttl = ttl
+		entry.size = size
+		entry.lastAccess = now
+	} else {
+		el := c.ll.PushFront(&cacheEntry{key: key, value: value, expireTime: expireTime, hasExpiry: hasExpiry, ttl: ttl, size: size, lastAccess: now})
+		c.items[key] = el
+		c.currentBytes += size
+		atomic.AddInt64(c.totalSize, 1)
+		atomic.AddInt64(c.totalBytes, size)
+	}
+
+	if len(c.shards) > 1 {
+		c.mu.Unlock()
+		c.enforceGlobalLimits(key)
+		return
+	}
+
+	el := c.items[key]
+	c.enforceLimits(el)
+	c.mu.Unlock()
+}
+
+// Get retrieves a value from the cache given a key. On a hit, unless
+// SkipTTLExtensionOnHit is set, the entry's TTL is refreshed (sliding-window
+// semantics) and it moves to the front of the LRU list. On a miss, or if the
+// entry has expired, it falls back to the loader function if one is set.
+func (c *cacheShard) Get(key string) interface{} {
+	c.mu.Lock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		c.mu.Unlock()
+		return c.loadOnMiss(key)
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if entry.hasExpiry && entry.expireTime.Before(c.clock.Now()) {
+		c.removeElement(el)
+		c.misses++
+		value := entry.value
+		cb := c.expirationCallback
+		c.mu.Unlock()
+		if cb != nil {
+			cb(key, value)
+		}
+		return c.loadOnMiss(key)
+	}
+
+	if !c.skipTTLExtensionOnHit && entry.hasExpiry {
+		entry.expireTime = c.clock.Now().Add(entry.ttl)
+	}
+
+	entry.hitCount++
+	entry.lastAccess = c.clock.Now()
+	c.hits++
+	c.ll.MoveToFront(el)
+	value := entry.value
+	c.mu.Unlock()
+	return value
+}
+
+// Delete removes a value from the cache given a key
+func (c *cacheShard) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// removeElement removes el from both the list and the lookup map, and
+// releases its share of currentBytes and of the cache-wide totalSize/
+// totalBytes counters. Callers must hold c.mu.
+func (c *cacheShard) removeElement(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.ll.Remove(el)
+	delete(c.items, entry.key)
+	c.currentBytes -= entry.size
+	atomic.AddInt64(c.totalSize, -1)
+	atomic.AddInt64(c.totalBytes, -entry.size)
+}
+
+// HandleCreateCache is the handler for creating a new cache with a given
+// maximum size and expiration TTL. An optional "cleanupInterval" query
+// parameter (in seconds) starts a janitor goroutine that proactively
+// reclaims expired entries; if omitted, entries are only reclaimed lazily.
+func HandleCreateCache(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	maxSize := vars["maxSize"]
+	expireTTL := vars["expireTTL"]
+
+	maxSizeInt := 0
+	expireTTLInt := 0
+
+	fmt.Sscanf(maxSize, "%d", &maxSizeInt)
+	fmt.Sscanf(expireTTL, "%d", &expireTTLInt)
+
+	if maxSizeInt <= 0 || expireTTLInt <= 0 {
+		http.Error(w, "Invalid input. Maximum size and expiration TTL must be greater than 0.", http.StatusBadRequest)
+		return
+	}
+
+	cleanupIntervalInt := 0
+	if raw := r.URL.Query().Get("cleanupInterval"); raw != "" {
+		fmt.Sscanf(raw, "%d", &cleanupIntervalInt)
+	}
+
+	var maxBytesInt int64
+	if raw := r.URL.Query().Get("maxBytes"); raw != "" {
+		fmt.Sscanf(raw, "%d", &maxBytesInt)
+	}
+
+	policy, err := ParseEvictionPolicy(r.URL.Query().Get("policy"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cache := NewCacheWithOptions(CacheOptions{
+		MaxSize:         maxSizeInt,
+		MaxBytes:        maxBytesInt,
+		ExpireTTL:       time.Duration(expireTTLInt) * time.Second,
+		CleanupInterval: time.Duration(cleanupIntervalInt) * time.Second,
+		Clock:           realClock{},
+		EvictionPolicy:  policy,
+	})
+
+	cacheID := fmt.Sprintf("cache%d", time.Now().UnixNano())
+
+	cacheMapInstance.mu.Lock()
+	defer cacheMapInstance.mu.Unlock()
+
+	cacheMapInstance.caches[cacheID] = cache
+
+	response := map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Cache created with ID: %s", cacheID),
+	}
+
+	WriteJSONResponse(w, http.StatusCreated, response)
+}
+
+// HandleDeleteCache is the handler for removing a cache by ID, stopping its
+// janitor goroutine (if any) before dropping it from cacheMapInstance.
+func HandleDeleteCache(w http.ResponseWriter, r *http.Request) {
+	cacheID := mux.Vars(r)["cacheID"]
+
+	cacheMapInstance.mu.Lock()
+	defer cacheMapInstance.mu.Unlock()
+
+	cache, ok := cacheMapInstance.caches[cacheID]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Cache not found: %s", cacheID), http.StatusNotFound)
+		return
+	}
+
+	cache.Close()
+	delete(cacheMapInstance.caches, cacheID)
+
+	response := map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Cache deleted: %s", cacheID),
+	}
+
+	WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// In this implementation, a unique cache ID is generated based on the current UnixNano timestamp, and the created cache is stored in the cacheMapInstance
+// which is a global instance of cacheMap that holds multiple caches identified by their cache IDs. Also,
+// the WriteJSONResponse function is used to write the J6SON response to the HTTP response writer with the appropriate status code and response body.
+// Plz you have to the Gorilla Mux library installed in your Go environment. You can install it using this command:go get -u github.com/gorilla/mux
+// Thank you.
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock whose Now is advanced explicitly by tests, so expiry
+// and eviction can be exercised deterministically instead of via time.Sleep.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
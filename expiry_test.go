@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCacheShard_ExpiresWithFakeClock exercises the expiry path
+// deterministically via an injected Clock, rather than sleeping past a real
+// TTL: a fakeClock lets the test jump straight past the expiration instant.
+func TestCacheShard_ExpiresWithFakeClock(t *testing.T) {
+	clk := newFakeClock()
+	var totalSize, totalBytes int64
+	shard := newCacheShard(CacheOptions{MaxSize: 10, ExpireTTL: time.Minute, Clock: clk}, &totalSize, &totalBytes)
+	defer shard.Close()
+
+	shard.Set("a", 1)
+	if got := shard.Get("a"); got != 1 {
+		t.Fatalf("Get(%q) before expiry = %v, want 1", "a", got)
+	}
+
+	clk.Advance(2 * time.Minute)
+
+	if got := shard.Get("a"); got != nil {
+		t.Fatalf("Get(%q) after TTL elapsed = %v, want nil", "a", got)
+	}
+	if got := shard.Stats().Entries; got != 0 {
+		t.Fatalf("Entries after expiry = %d, want 0", got)
+	}
+}
+
+// TestCacheShard_SkipTTLExtensionOnHit exercises the DNS-style TTL-on-hit
+// mode with a fakeClock: with SkipTTLExtensionOnHit set, a Get before expiry
+// does not push the deadline out, so the entry still expires at its original
+// TTL.
+func TestCacheShard_SkipTTLExtensionOnHit(t *testing.T) {
+	clk := newFakeClock()
+	var totalSize, totalBytes int64
+	shard := newCacheShard(CacheOptions{MaxSize: 10, ExpireTTL: time.Minute, Clock: clk}, &totalSize, &totalBytes)
+	defer shard.Close()
+	shard.SkipTTLExtensionOnHit(true)
+
+	shard.Set("a", 1)
+	clk.Advance(30 * time.Second)
+	if got := shard.Get("a"); got != 1 {
+		t.Fatalf("Get(%q) at 30s = %v, want 1", "a", got)
+	}
+
+	clk.Advance(40 * time.Second)
+	if got := shard.Get("a"); got != nil {
+		t.Fatalf("Get(%q) at 70s with SkipTTLExtensionOnHit = %v, want nil (TTL should not have been extended)", "a", got)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "time"
+
+// runJanitor periodically walks the cache removing entries whose TTL has
+// passed, so memory doesn't grow unbounded between accesses. It exits once
+// Close is called.
+func (c *cacheShard) runJanitor() {
+	ticker := time.NewTicker(c.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.deleteExpired()
+		case <-c.stopJanitor:
+			return
+		}
+	}
+}
+
+// deleteExpired removes every entry whose TTL has passed, invoking the
+// expiration callback (if any) for each one. Entries are kept in recency
+// order, not expiry order, so this walks the whole list.
+func (c *cacheShard) deleteExpired() {
+	c.mu.Lock()
+	var expired []cacheEntry
+	now := c.clock.Now()
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*cacheEntry)
+		if entry.hasExpiry && entry.expireTime.Before(now) {
+			expired = append(expired, *entry)
+			c.removeElement(el)
+		}
+		el = next
+	}
+	cb := c.expirationCallback
+	c.mu.Unlock()
+
+	if cb != nil {
+		for _, entry := range expired {
+			cb(entry.key, entry.value)
+		}
+	}
+}
+
+// Close stops the janitor goroutine, if one was started. It is safe to call
+// more than once and safe to call on a Cache created without a janitor.
+func (c *cacheShard) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stopJanitor)
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// loaderCall tracks a single in-flight call to the loader function for a
+// given key, so that concurrent Get misses on the same key run the loader
+// exactly once and all callers observe the same result.
+type loaderCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	ttl time.Duration
+	err error
+}
+
+// SetExpirationCallback registers a function invoked whenever an entry is
+// found to have expired, either by the janitor or lazily by Get. Pass nil to
+// disable it.
+func (c *cacheShard) SetExpirationCallback(cb func(key string, value interface{})) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expirationCallback = cb
+}
+
+// SetLoaderFunction registers a function that Get calls on a cache miss to
+// populate the entry. The returned duration is used as that entry's TTL
+// (NoExpiration is honored). Pass nil to disable loading on miss.
+func (c *cacheShard) SetLoaderFunction(fn func(key string) (interface{}, time.Duration, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaderFunction = fn
+}
+
+// SkipTTLExtensionOnHit toggles whether a Get hit refreshes the entry's TTL.
+// false (the default) is sliding-window semantics: every hit extends the
+// TTL. true is DNS-style semantics: the TTL set at Set time is fixed.
+func (c *cacheShard) SkipTTLExtensionOnHit(skip bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipTTLExtensionOnHit = skip
+}
+
+// loadOnMiss runs the loader function for key, coalescing concurrent callers
+// for the same key into a single call. It returns nil if no loader is set or
+// the loader errors.
+func (c *cacheShard) loadOnMiss(key string) interface{} {
+	c.mu.Lock()
+	loader := c.loaderFunction
+	c.mu.Unlock()
+
+	if loader == nil {
+		return nil
+	}
+
+	c.loaderMu.Lock()
+	if call, ok := c.loaderCalls[key]; ok {
+		c.loaderMu.Unlock()
+		call.wg.Wait()
+		if call.err != nil {
+			return nil
+		}
+		return call.val
+	}
+
+	call := &loaderCall{}
+	call.wg.Add(1)
+	c.loaderCalls[key] = call
+	c.loaderMu.Unlock()
+
+	call.val, call.ttl, call.err = loader(key)
+
+	c.loaderMu.Lock()
+	delete(c.loaderCalls, key)
+	c.loaderMu.Unlock()
+	call.wg.Done()
+
+	if call.err != nil {
+		return nil
+	}
+
+	c.SetWithTTL(key, call.val, call.ttl)
+	return call.val
+}
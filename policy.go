@@ -0,0 +1,284 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// EvictionPolicy selects which entry enforceLimits picks as the next victim
+// once the cache is over its size or byte budget.
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least-recently-used entry (the default).
+	PolicyLRU EvictionPolicy = iota
+	// PolicyLFU evicts the least-frequently-used entry, breaking ties by
+	// recency.
+	PolicyLFU
+	// PolicyOldestExpiry evicts the entry closest to expiring. Entries with
+	// no expiration are never chosen unless every entry lacks one, in which
+	// case this falls back to PolicyLRU.
+	PolicyOldestExpiry
+)
+
+// ParseEvictionPolicy maps the "policy" query parameter accepted by
+// HandleCreateCache to an EvictionPolicy. An empty string yields PolicyLRU.
+func ParseEvictionPolicy(raw string) (EvictionPolicy, error) {
+	switch raw {
+	case "", "lru":
+		return PolicyLRU, nil
+	case "lfu":
+		return PolicyLFU, nil
+	case "oldest_expiry":
+		return PolicyOldestExpiry, nil
+	default:
+		return PolicyLRU, fmt.Errorf("unknown eviction policy: %s", raw)
+	}
+}
+
+// Sizer estimates the in-memory size, in bytes, of a cached value. It is
+// used to enforce CacheOptions.MaxBytes.
+type Sizer func(value interface{}) int64
+
+// DefaultSizer sizes []byte and string values by their length, and falls
+// back to unsafe.Sizeof for everything else. The fallback is a rough
+// estimate — unsafe.Sizeof of an interface{} is the size of the interface
+// header itself, the same for every dynamic type, not the size of the value
+// it holds or of anything that value points to.
+func DefaultSizer(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	default:
+		return int64(unsafe.Sizeof(value))
+	}
+}
+
+// CacheOptions configures NewCacheWithOptions and, per shard, newCacheShard.
+// Zero-value fields fall back to sane defaults: MaxBytes of 0 means no byte
+// budget, Clock defaults to the system clock, Sizer defaults to
+// DefaultSizer, and EvictionPolicy defaults to PolicyLRU.
+type CacheOptions struct {
+	MaxSize         int
+	MaxBytes        int64
+	ExpireTTL       time.Duration
+	CleanupInterval time.Duration
+	Clock           Clock
+	Sizer           Sizer
+	EvictionPolicy  EvictionPolicy
+}
+
+// newCacheShard creates a single cacheShard from the given CacheOptions.
+// totalSize and totalBytes are atomic counters shared by every shard of the
+// same Cache, so that MaxSize/MaxBytes are enforced as one cache-wide
+// budget rather than per shard; pass a counter private to this shard alone
+// to use it standalone (e.g. in a benchmark).
+func newCacheShard(opts CacheOptions, totalSize, totalBytes *int64) *cacheShard {
+	clock := opts.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	sizer := opts.Sizer
+	if sizer == nil {
+		sizer = DefaultSizer
+	}
+
+	c := &cacheShard{
+		ll:              list.New(),
+		items:           make(map[string]*list.Element),
+		maxSize:         opts.MaxSize,
+		maxBytes:        opts.MaxBytes,
+		totalSize:       totalSize,
+		totalBytes:      totalBytes,
+		sizer:           sizer,
+		policy:          opts.EvictionPolicy,
+		expireTTL:       opts.ExpireTTL,
+		clock:           clock,
+		cleanupInterval: opts.CleanupInterval,
+		stopJanitor:     make(chan struct{}),
+		loaderCalls:     make(map[string]*loaderCall),
+	}
+
+	if opts.CleanupInterval > 0 {
+		go c.runJanitor()
+	}
+
+	return c
+}
+
+// enforceLimits evicts entries, via the configured EvictionPolicy, until the
+// cache is within maxSize and maxBytes. protected is never evicted unless it
+// is the only entry left. Callers must hold c.mu.
+func (c *cacheShard) enforceLimits(protected *list.Element) {
+	for c.overLimit() {
+		victim := c.pickVictim(protected)
+		if victim == nil {
+			return
+		}
+		c.removeElement(victim)
+		c.evictions++
+	}
+}
+
+// overLimit reports whether the cache-wide entry or byte count (shared
+// across every shard via totalSize/totalBytes) currently exceeds maxSize or
+// maxBytes. Callers must hold c.mu.
+func (c *cacheShard) overLimit() bool {
+	if c.maxSize > 0 && atomic.LoadInt64(c.totalSize) > int64(c.maxSize) {
+		return true
+	}
+	if c.maxBytes > 0 && atomic.LoadInt64(c.totalBytes) > c.maxBytes {
+		return true
+	}
+	return false
+}
+
+// pickVictim selects the next entry to evict per the configured
+// EvictionPolicy, never returning protected unless it's the only entry left.
+// Callers must hold c.mu.
+func (c *cacheShard) pickVictim(protected *list.Element) *list.Element {
+	switch c.policy {
+	case PolicyLFU:
+		return c.pickLFUVictim(protected)
+	case PolicyOldestExpiry:
+		return c.pickOldestExpiryVictim(protected)
+	default:
+		return c.pickLRUVictim(protected)
+	}
+}
+
+// pickLRUVictim returns the least-recently-used element, i.e. the one at the
+// back of the list, skipping protected if anything else is available.
+func (c *cacheShard) pickLRUVictim(protected *list.Element) *list.Element {
+	if victim := c.pickLRUVictimExcluding(protected); victim != nil {
+		return victim
+	}
+	return protected
+}
+
+// pickLFUVictim returns the element with the lowest hit count, skipping
+// protected if anything else is available.
+func (c *cacheShard) pickLFUVictim(protected *list.Element) *list.Element {
+	if victim := c.pickLFUVictimExcluding(protected); victim != nil {
+		return victim
+	}
+	return protected
+}
+
+// pickOldestExpiryVictim returns the element closest to expiring, skipping
+// protected if anything else is available. If no entry has an expiration it
+// falls back to LRU order.
+func (c *cacheShard) pickOldestExpiryVictim(protected *list.Element) *list.Element {
+	if victim := c.pickOldestExpiryVictimExcluding(protected); victim != nil {
+		return victim
+	}
+	return protected
+}
+
+// pickLRUVictimExcluding is pickLRUVictim without the protected fallback: it
+// returns nil, rather than protected, when protected is the only entry.
+// enforceGlobalLimits uses this to collect a shard's nomination without
+// risking re-removing protected.
+func (c *cacheShard) pickLRUVictimExcluding(protected *list.Element) *list.Element {
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		if el != protected {
+			return el
+		}
+	}
+	return nil
+}
+
+// pickLFUVictimExcluding is pickLFUVictim without the protected fallback.
+// See pickLRUVictimExcluding.
+func (c *cacheShard) pickLFUVictimExcluding(protected *list.Element) *list.Element {
+	var victim *list.Element
+	var victimHits uint64
+
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		if el == protected {
+			continue
+		}
+		hits := el.Value.(*cacheEntry).hitCount
+		if victim == nil || hits < victimHits {
+			victim = el
+			victimHits = hits
+		}
+	}
+
+	return victim
+}
+
+// pickOldestExpiryVictimExcluding is pickOldestExpiryVictim without the
+// protected fallback. See pickLRUVictimExcluding.
+func (c *cacheShard) pickOldestExpiryVictimExcluding(protected *list.Element) *list.Element {
+	var victim *list.Element
+	var victimExpiry time.Time
+
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		if el == protected {
+			continue
+		}
+		entry := el.Value.(*cacheEntry)
+		if !entry.hasExpiry {
+			continue
+		}
+		if victim == nil || entry.expireTime.Before(victimExpiry) {
+			victim = el
+			victimExpiry = entry.expireTime
+		}
+	}
+
+	if victim != nil {
+		return victim
+	}
+	return c.pickLRUVictimExcluding(protected)
+}
+
+// peekVictim returns a copy of this shard's local eviction candidate per the
+// configured EvictionPolicy, without removing it, for enforceGlobalLimits to
+// compare against other shards' candidates. If skipKey is non-empty, the
+// entry under that key (the one the caller just inserted) is excluded from
+// consideration. ok is false if the shard has no eligible candidate.
+func (c *cacheShard) peekVictim(skipKey string) (entry cacheEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var protected *list.Element
+	if skipKey != "" {
+		protected = c.items[skipKey]
+	}
+
+	var el *list.Element
+	switch c.policy {
+	case PolicyLFU:
+		el = c.pickLFUVictimExcluding(protected)
+	case PolicyOldestExpiry:
+		el = c.pickOldestExpiryVictimExcluding(protected)
+	default:
+		el = c.pickLRUVictimExcluding(protected)
+	}
+	if el == nil {
+		return cacheEntry{}, false
+	}
+	return *el.Value.(*cacheEntry), true
+}
+
+// evictKey removes key from this shard, if still present, and counts it as
+// an eviction. Used by enforceGlobalLimits once pickGlobalVictim has chosen
+// a victim; the "if still present" guards against the victim having already
+// been removed (e.g. by a concurrent Delete) between the peek and the evict.
+func (c *cacheShard) evictKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+		c.evictions++
+	}
+}
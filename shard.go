@@ -0,0 +1,220 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// shardsCount is the number of shards a Cache is split into, matching the
+// default used by k8s.io/apimachinery/pkg/util/cache.
+const shardsCount = 32
+
+// Cache is a sharded LRU+TTL cache: shardsCount independent cacheShards,
+// each with its own mutex, map and LRU list. Keys are routed to a shard by
+// an fnv-1a hash, so Get/Set for different keys no longer serialize behind
+// one lock, fixing the throughput collapse a single sync.Mutex causes under
+// concurrent load.
+type Cache struct {
+	shards []*cacheShard
+}
+
+// NewCache creates a new Cache with the given maximum size and expiration
+// TTL. The janitor is disabled; expired entries are only reclaimed lazily,
+// on access. Use NewCacheWithJanitor, or NewCacheWithOptions directly, to
+// reclaim them proactively or to set a byte budget / eviction policy.
+func NewCache(maxSize int, expireTTL time.Duration) *Cache {
+	return NewCacheWithClock(maxSize, expireTTL, realClock{})
+}
+
+// NewCacheWithClock creates a new Cache using the given Clock instead of the
+// system clock, primarily so tests can advance time deterministically. Like
+// NewCache, it does not start a janitor.
+func NewCacheWithClock(maxSize int, expireTTL time.Duration, clock Clock) *Cache {
+	return NewCacheWithJanitor(maxSize, expireTTL, 0, clock)
+}
+
+// NewCacheWithJanitor creates a new Cache and, if cleanupInterval > 0, starts
+// a background goroutine per shard that periodically removes expired
+// entries so they don't linger in memory between accesses. Callers are
+// responsible for calling Close once the cache is no longer needed to stop
+// those goroutines.
+func NewCacheWithJanitor(maxSize int, expireTTL, cleanupInterval time.Duration, clock Clock) *Cache {
+	return NewCacheWithOptions(CacheOptions{
+		MaxSize:         maxSize,
+		ExpireTTL:       expireTTL,
+		CleanupInterval: cleanupInterval,
+		Clock:           clock,
+	})
+}
+
+// NewCacheWithOptions creates a new sharded Cache from the given
+// CacheOptions. MaxSize and MaxBytes stay whole, cache-wide budgets — each
+// shard shares a pair of atomic counters (rather than getting 1/shardsCount
+// of the budget each) so that, e.g., a cache created with MaxSize 1 still
+// holds at most 1 entry total, not 1 per shard. It is the most general
+// constructor; NewCache, NewCacheWithClock and NewCacheWithJanitor are thin
+// convenience wrappers around it.
+func NewCacheWithOptions(opts CacheOptions) *Cache {
+	var totalSize, totalBytes int64
+
+	shards := make([]*cacheShard, shardsCount)
+	for i := range shards {
+		shards[i] = newCacheShard(opts, &totalSize, &totalBytes)
+	}
+	for _, s := range shards {
+		s.shards = shards
+	}
+	return &Cache{shards: shards}
+}
+
+// shardFor returns the shard responsible for key, selected by an fnv-1a
+// hash of the key bytes modulo the shard count.
+func (c *Cache) shardFor(key string) *cacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+// Set stores a value in the cache with the given key, using the cache-wide
+// expireTTL. It moves the entry to the front of its shard's LRU list.
+func (c *Cache) Set(key string, value interface{}) {
+	c.shardFor(key).Set(key, value)
+}
+
+// SetWithTTL stores a value in the cache with the given key, overriding the
+// cache-wide expireTTL for this entry. Pass NoExpiration for an entry that
+// should never expire.
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.shardFor(key).SetWithTTL(key, value, ttl)
+}
+
+// Get retrieves a value from the cache given a key. See cacheShard.Get for
+// the hit/miss/loader semantics.
+func (c *Cache) Get(key string) interface{} {
+	return c.shardFor(key).Get(key)
+}
+
+// Delete removes a value from the cache given a key.
+func (c *Cache) Delete(key string) {
+	c.shardFor(key).Delete(key)
+}
+
+// SetExpirationCallback registers cb on every shard. See
+// cacheShard.SetExpirationCallback.
+func (c *Cache) SetExpirationCallback(cb func(key string, value interface{})) {
+	for _, s := range c.shards {
+		s.SetExpirationCallback(cb)
+	}
+}
+
+// SetLoaderFunction registers fn on every shard. See
+// cacheShard.SetLoaderFunction.
+func (c *Cache) SetLoaderFunction(fn func(key string) (interface{}, time.Duration, error)) {
+	for _, s := range c.shards {
+		s.SetLoaderFunction(fn)
+	}
+}
+
+// SkipTTLExtensionOnHit sets the TTL-on-hit mode on every shard. See
+// cacheShard.SkipTTLExtensionOnHit.
+func (c *Cache) SkipTTLExtensionOnHit(skip bool) {
+	for _, s := range c.shards {
+		s.SkipTTLExtensionOnHit(skip)
+	}
+}
+
+// Close stops every shard's janitor goroutine, if any were started. It is
+// safe to call more than once.
+func (c *Cache) Close() {
+	for _, s := range c.shards {
+		s.Close()
+	}
+}
+
+// enforceGlobalLimits evicts entries across every shard of c.shards, via the
+// configured EvictionPolicy, until the cache-wide entry/byte budget (shared
+// across all shards through totalSize/totalBytes) is satisfied. protectedKey
+// is the entry c just inserted; it is only evicted if it ends up the sole
+// entry across the whole cache. c must not be holding c.mu.
+func (c *cacheShard) enforceGlobalLimits(protectedKey string) {
+	for {
+		size := atomic.LoadInt64(c.totalSize)
+		bytes := atomic.LoadInt64(c.totalBytes)
+		if (c.maxSize <= 0 || size <= int64(c.maxSize)) && (c.maxBytes <= 0 || bytes <= c.maxBytes) {
+			return
+		}
+
+		shard, key, ok := c.pickGlobalVictim(protectedKey)
+		if !ok {
+			// protectedKey is the only entry left; evict it too if the
+			// cache is still over budget, as enforceLimits does.
+			c.mu.Lock()
+			over := c.overLimit()
+			c.mu.Unlock()
+			if over {
+				c.evictKey(protectedKey)
+			}
+			return
+		}
+		shard.evictKey(key)
+	}
+}
+
+// pickGlobalVictim scans every shard for its local eviction candidate (via
+// peekVictim, skipping protectedKey in the shard that owns it) and returns
+// whichever candidate isWorseVictim ranks worst across the whole cache.
+func (c *cacheShard) pickGlobalVictim(protectedKey string) (victimShard *cacheShard, victimKey string, ok bool) {
+	var best cacheEntry
+	found := false
+
+	for _, s := range c.shards {
+		skipKey := ""
+		if s == c {
+			skipKey = protectedKey
+		}
+
+		entry, candidateOK := s.peekVictim(skipKey)
+		if !candidateOK {
+			continue
+		}
+		if !found || c.isWorseVictim(entry, best) {
+			best = entry
+			victimShard = s
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, "", false
+	}
+	return victimShard, best.key, true
+}
+
+// isWorseVictim reports whether a should be evicted before b, per c's
+// configured EvictionPolicy:
+//   - PolicyLFU: the lower hit count is worse; on equal hit counts, the
+//     older lastAccess is worse.
+//   - PolicyOldestExpiry: an entry with an expiration is always worse than
+//     one without; between two with an expiration, the earlier one is
+//     worse; between two without, falls back to PolicyLRU.
+//   - PolicyLRU (the default): the older lastAccess is worse.
+func (c *cacheShard) isWorseVictim(a, b cacheEntry) bool {
+	switch c.policy {
+	case PolicyLFU:
+		if a.hitCount != b.hitCount {
+			return a.hitCount < b.hitCount
+		}
+		return a.lastAccess.Before(b.lastAccess)
+	case PolicyOldestExpiry:
+		if a.hasExpiry != b.hasExpiry {
+			return a.hasExpiry
+		}
+		if a.hasExpiry {
+			return a.expireTime.Before(b.expireTime)
+		}
+		return a.lastAccess.Before(b.lastAccess)
+	default:
+		return a.lastAccess.Before(b.lastAccess)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarkGetSetMix drives a concurrent 90% Get / 10% Set mix over a fixed
+// key space against whatever get/set funcs the caller supplies, so the same
+// workload can be replayed against a single cacheShard and against a full,
+// shardsCount-way Cache.
+func benchmarkGetSetMix(b *testing.B, get func(key string) interface{}, set func(key string, value interface{})) {
+	const keySpace = 1000
+	for i := 0; i < keySpace; i++ {
+		set(fmt.Sprintf("key-%d", i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := fmt.Sprintf("key-%d", i%keySpace)
+			if i%10 == 0 {
+				set(key, i)
+			} else {
+				get(key)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkSingleMutex exercises a lone cacheShard, i.e. the pre-sharding
+// design where every Get/Set across the whole cache serializes behind one
+// sync.Mutex.
+func BenchmarkSingleMutex(b *testing.B) {
+	var totalSize, totalBytes int64
+	shard := newCacheShard(CacheOptions{
+		MaxSize:   10000,
+		ExpireTTL: time.Minute,
+		Clock:     realClock{},
+	}, &totalSize, &totalBytes)
+	defer shard.Close()
+
+	benchmarkGetSetMix(b, shard.Get, shard.Set)
+}
+
+// BenchmarkSharded exercises the full, shardsCount-way sharded Cache, where
+// Get/Set for keys in different shards run under independent locks.
+func BenchmarkSharded(b *testing.B) {
+	c := NewCacheWithOptions(CacheOptions{
+		MaxSize:   10000,
+		ExpireTTL: time.Minute,
+		Clock:     realClock{},
+	})
+	defer c.Close()
+
+	benchmarkGetSetMix(b, c.Get, c.Set)
+}
+
+// distinctShardKeys returns two keys that hash to different shards of c, so
+// tests can exercise cross-shard eviction deterministically rather than
+// hoping a couple of hardcoded keys happen to collide or not.
+func distinctShardKeys(t *testing.T, c *Cache) (string, string) {
+	t.Helper()
+
+	first := c.shardFor("key-0")
+	for i := 1; i < 10000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if c.shardFor(key) != first {
+			return "key-0", key
+		}
+	}
+	t.Fatal("could not find two keys that hash to different shards")
+	return "", ""
+}
+
+// TestCache_GlobalLRUAcrossShards is a regression test for a cache-wide
+// MaxSize being enforced per shard instead of across the whole Cache: with
+// MaxSize 1, setting two keys that land in different shards must evict the
+// least-recently-set one globally ("a"), not just trim whichever shard the
+// new key ("b") happened to land in.
+func TestCache_GlobalLRUAcrossShards(t *testing.T) {
+	c := NewCacheWithOptions(CacheOptions{MaxSize: 1, ExpireTTL: time.Minute, Clock: realClock{}})
+	defer c.Close()
+
+	keyA, keyB := distinctShardKeys(t, c)
+
+	c.Set(keyA, "a")
+	c.Set(keyB, "b")
+
+	if got := c.Get(keyB); got != "b" {
+		t.Fatalf("Get(%q) = %v, want %q (most recently set entry should survive)", keyB, got, "b")
+	}
+	if got := c.Get(keyA); got != nil {
+		t.Fatalf("Get(%q) = %v, want nil (least recently used entry should have been evicted)", keyA, got)
+	}
+	if got := c.Stats().Entries; got != 1 {
+		t.Fatalf("Stats().Entries = %d, want 1", got)
+	}
+}
+
+// TestCache_ConcurrentInsertsKeepCountersNonNegative is a regression test
+// for enforceGlobalLimits re-removing an already-evicted protected entry
+// under concurrent inserts into different shards, which used to double
+// decrement totalSize/totalBytes into negative territory. It doesn't prove
+// the race can't happen, but it exercises the path that used to corrupt the
+// counters.
+func TestCache_ConcurrentInsertsKeepCountersNonNegative(t *testing.T) {
+	c := NewCacheWithOptions(CacheOptions{MaxSize: 4, ExpireTTL: time.Minute, Clock: realClock{}})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Set(fmt.Sprintf("key-%d", i), i)
+		}(i)
+	}
+	wg.Wait()
+
+	stats := c.Stats()
+	if stats.Entries < 0 || stats.Entries > 4 {
+		t.Fatalf("Entries = %d, want in [0, 4]", stats.Entries)
+	}
+	if stats.Bytes < 0 {
+		t.Fatalf("Bytes = %d, want >= 0", stats.Bytes)
+	}
+}
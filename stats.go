@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CacheStats is a snapshot of a cacheShard's runtime counters. Cache.Stats
+// sums these across every shard; Cache.ShardStats reports them individually.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Bytes     int64  `json:"bytes"`
+	Entries   int    `json:"entries"`
+}
+
+// Stats returns a snapshot of the shard's hit/miss/eviction counters along
+// with its current byte and entry counts.
+func (c *cacheShard) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Bytes:     c.currentBytes,
+		Entries:   c.ll.Len(),
+	}
+}
+
+// Stats returns the sum of every shard's hit/miss/eviction counters and
+// byte/entry counts, i.e. the cache's stats as a whole.
+func (c *Cache) Stats() CacheStats {
+	var total CacheStats
+	for _, s := range c.shards {
+		st := s.Stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Evictions += st.Evictions
+		total.Bytes += st.Bytes
+		total.Entries += st.Entries
+	}
+	return total
+}
+
+// ShardStats returns each shard's stats individually, in shard order, for
+// inspecting whether keys are distributed evenly across shards.
+func (c *Cache) ShardStats() []CacheStats {
+	stats := make([]CacheStats, len(c.shards))
+	for i, s := range c.shards {
+		stats[i] = s.Stats()
+	}
+	return stats
+}
+
+// HandleCacheStats is the handler for reporting a cache's hit/miss/eviction
+// counters plus its current byte and entry counts as JSON.
+func HandleCacheStats(w http.ResponseWriter, r *http.Request) {
+	cacheID := mux.Vars(r)["cacheID"]
+
+	cacheMapInstance.mu.RLock()
+	cache, ok := cacheMapInstance.caches[cacheID]
+	cacheMapInstance.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("Cache not found: %s", cacheID), http.StatusNotFound)
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, cache.Stats())
+}
+
+// HandleCacheShardStats is the debug handler for reporting a cache's
+// per-shard stats as a JSON array, useful for checking that keys are
+// distributed evenly across shards.
+func HandleCacheShardStats(w http.ResponseWriter, r *http.Request) {
+	cacheID := mux.Vars(r)["cacheID"]
+
+	cacheMapInstance.mu.RLock()
+	cache, ok := cacheMapInstance.caches[cacheID]
+	cacheMapInstance.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("Cache not found: %s", cacheID), http.StatusNotFound)
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, cache.ShardStats())
+}
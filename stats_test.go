@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+// TestCache_GlobalByteBudgetAcrossShards is a regression test for MaxBytes
+// being enforced per shard instead of cache-wide: a single large value set
+// into an otherwise-empty shard must not survive just because its own shard
+// was under budget — the budget is shared, so it must evict older, smaller
+// entries in other shards until the total is back under MaxBytes.
+func TestCache_GlobalByteBudgetAcrossShards(t *testing.T) {
+	c := NewCacheWithOptions(CacheOptions{MaxBytes: 12, ExpireTTL: 0, Clock: realClock{}})
+	defer c.Close()
+
+	keyA, keyB := distinctShardKeys(t, c)
+
+	c.SetWithTTL(keyA, []byte("0123456789"), NoExpiration) // 10 bytes
+	c.SetWithTTL(keyB, []byte("abcde"), NoExpiration)      // 5 bytes, pushes total to 15 > 12
+
+	if got := c.Stats().Bytes; got > 12 {
+		t.Fatalf("Stats().Bytes = %d, want <= 12", got)
+	}
+	if got := c.Get(keyB); got == nil {
+		t.Fatalf("Get(%q) = nil, want the most recently set value to survive", keyB)
+	}
+	if got := c.Get(keyA); got != nil {
+		t.Fatalf("Get(%q) = %v, want nil (oldest entry should have been evicted to stay under MaxBytes)", keyA, got)
+	}
+}
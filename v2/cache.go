@@ -0,0 +1,194 @@
+// Package cache is the v2, generic sibling of the root package's Cache. The
+// interface{}-based Cache stays put to keep the HTTP layer unchanged; this
+// package is for internal Go consumers that want compile-time type safety,
+// e.g. cache.New[string, *User](1024, time.Minute).
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Clock abstracts time.Now so tests can inject virtual time instead of
+// sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock backed by the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// Cache is a generic, type-safe LRU cache with per-entry expiration. It
+// mirrors the LRU-list-plus-map design and janitor of the root package's
+// Cache, but keys and values are compile-time typed instead of
+// interface{}.
+type Cache[K comparable, V any] struct {
+	mu              sync.Mutex
+	ll              *list.List
+	items           map[K]*list.Element
+	maxSize         int
+	expireTTL       time.Duration
+	clock           Clock
+	cleanupInterval time.Duration
+	stopJanitor     chan struct{}
+	stopOnce        sync.Once
+}
+
+// entry is the value stored in each list.Element.
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	expireTime time.Time
+}
+
+// New creates a new Cache with the given maximum size and expiration TTL.
+// The janitor is disabled; expired entries are only reclaimed lazily, on
+// access. Use NewWithJanitor to reclaim them proactively.
+func New[K comparable, V any](maxSize int, expireTTL time.Duration) *Cache[K, V] {
+	return NewWithClock[K, V](maxSize, expireTTL, realClock{})
+}
+
+// NewWithClock creates a new Cache using the given Clock instead of the
+// system clock, primarily so tests can advance time deterministically.
+func NewWithClock[K comparable, V any](maxSize int, expireTTL time.Duration, clock Clock) *Cache[K, V] {
+	return NewWithJanitor[K, V](maxSize, expireTTL, 0, clock)
+}
+
+// NewWithJanitor creates a new Cache and, if cleanupInterval > 0, starts a
+// background goroutine that periodically removes expired entries. Callers
+// are responsible for calling Close once the cache is no longer needed to
+// stop that goroutine.
+func NewWithJanitor[K comparable, V any](maxSize int, expireTTL, cleanupInterval time.Duration, clock Clock) *Cache[K, V] {
+	c := &Cache[K, V]{
+		ll:              list.New(),
+		items:           make(map[K]*list.Element),
+		maxSize:         maxSize,
+		expireTTL:       expireTTL,
+		clock:           clock,
+		cleanupInterval: cleanupInterval,
+		stopJanitor:     make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go c.runJanitor()
+	}
+
+	return c
+}
+
+// Set stores value under key, moving it to the front of the LRU list. If key
+// already exists its value and position are refreshed rather than creating
+// a duplicate entry.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expireTime := c.clock.Now().Add(c.expireTTL)
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.expireTime = expireTime
+		return
+	}
+
+	if c.ll.Len() >= c.maxSize {
+		c.evictOldest()
+	}
+
+	el := c.ll.PushFront(&entry[K, V]{key: key, value: value, expireTime: expireTime})
+	c.items[key] = el
+}
+
+// Get retrieves the value stored under key. The second return value reports
+// whether key was present and not expired. A hit moves the entry to the
+// front of the LRU list.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	e := el.Value.(*entry[K, V])
+	if e.expireTime.Before(c.clock.Now()) {
+		c.removeElement(el)
+		var zero V
+		return zero, false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Delete removes the value stored under key, if any.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// evictOldest removes the least-recently-used entry, i.e. the one at the
+// back of the LRU list. Callers must hold c.mu.
+func (c *Cache[K, V]) evictOldest() {
+	el := c.ll.Back()
+	if el != nil {
+		c.removeElement(el)
+	}
+}
+
+// removeElement removes el from both the list and the lookup map. Callers
+// must hold c.mu.
+func (c *Cache[K, V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
+
+// runJanitor periodically removes expired entries. It exits once Close is
+// called.
+func (c *Cache[K, V]) runJanitor() {
+	ticker := time.NewTicker(c.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.deleteExpired()
+		case <-c.stopJanitor:
+			return
+		}
+	}
+}
+
+// deleteExpired removes every entry whose TTL has passed.
+func (c *Cache[K, V]) deleteExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*entry[K, V]).expireTime.Before(now) {
+			c.removeElement(el)
+		}
+		el = next
+	}
+}
+
+// Close stops the janitor goroutine, if one was started. It is safe to call
+// more than once and safe to call on a Cache created without a janitor.
+func (c *Cache[K, V]) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stopJanitor)
+	})
+}